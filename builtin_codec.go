@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/zjllib/gnet/errors"
+)
+
+// LengthPrefixCodec frames messages with a fixed-size length prefix ahead of the
+// payload. PrefixBytes must be 1, 2, 4 or 8; ByteOrder defaults to
+// binary.BigEndian when nil.
+type LengthPrefixCodec struct {
+	PrefixBytes int
+	ByteOrder   binary.ByteOrder
+}
+
+func (c *LengthPrefixCodec) order() binary.ByteOrder {
+	if c.ByteOrder != nil {
+		return c.ByteOrder
+	}
+	return binary.BigEndian
+}
+
+// Encode prepends a PrefixBytes-wide length header to buf.
+func (c *LengthPrefixCodec) Encode(_ Conn, buf []byte) ([]byte, error) {
+	out := make([]byte, c.PrefixBytes+len(buf))
+	c.putLength(out, len(buf))
+	copy(out[c.PrefixBytes:], buf)
+	return out, nil
+}
+
+// Decode reads the length prefix to determine how many bytes the frame needs, and
+// waits for the rest if they haven't arrived yet.
+func (c *LengthPrefixCodec) Decode(conn Conn) ([]byte, error) {
+	header, buf := conn.ReadN(c.PrefixBytes)
+	if header < c.PrefixBytes {
+		return nil, errors.ErrIncompletePacket
+	}
+
+	bodyLen := c.length(buf)
+	total := c.PrefixBytes + bodyLen
+	size, frame := conn.ReadN(total)
+	if size < total {
+		return nil, errors.ErrIncompletePacket
+	}
+
+	// Copy out of the ring buffer's memory before ShiftN, which is free to evict or
+	// reuse the region frame currently points into.
+	out := make([]byte, bodyLen)
+	copy(out, frame[c.PrefixBytes:])
+	conn.ShiftN(total)
+	return out, nil
+}
+
+func (c *LengthPrefixCodec) putLength(out []byte, n int) {
+	switch c.PrefixBytes {
+	case 1:
+		out[0] = byte(n)
+	case 2:
+		c.order().PutUint16(out, uint16(n))
+	case 4:
+		c.order().PutUint32(out, uint32(n))
+	case 8:
+		c.order().PutUint64(out, uint64(n))
+	}
+}
+
+func (c *LengthPrefixCodec) length(buf []byte) int {
+	switch c.PrefixBytes {
+	case 1:
+		return int(buf[0])
+	case 2:
+		return int(c.order().Uint16(buf))
+	case 4:
+		return int(c.order().Uint32(buf))
+	case 8:
+		return int(c.order().Uint64(buf))
+	default:
+		return 0
+	}
+}
+
+// DelimiterCodec frames messages by a delimiter byte sequence, e.g. "\n" or "\r\n".
+// The delimiter itself is stripped from decoded frames and is not expected to be
+// present in Encode's input.
+type DelimiterCodec struct {
+	Delimiter []byte
+}
+
+// Encode appends the delimiter to buf.
+func (c *DelimiterCodec) Encode(_ Conn, buf []byte) ([]byte, error) {
+	out := make([]byte, 0, len(buf)+len(c.Delimiter))
+	out = append(out, buf...)
+	out = append(out, c.Delimiter...)
+	return out, nil
+}
+
+// Decode scans for the next delimiter in conn's buffered data.
+func (c *DelimiterCodec) Decode(conn Conn) ([]byte, error) {
+	buf := conn.Read()
+	idx := bytes.Index(buf, c.Delimiter)
+	if idx == -1 {
+		return nil, errors.ErrIncompletePacket
+	}
+
+	frame := make([]byte, idx)
+	copy(frame, buf[:idx])
+	conn.ShiftN(idx + len(c.Delimiter))
+	return frame, nil
+}
+
+// FixedLengthCodec frames every message as exactly Size bytes.
+type FixedLengthCodec struct {
+	Size int
+}
+
+// Encode requires buf to already be exactly Size bytes long.
+func (c *FixedLengthCodec) Encode(_ Conn, buf []byte) ([]byte, error) {
+	return buf, nil
+}
+
+// Decode waits for Size bytes and returns them as a single frame.
+func (c *FixedLengthCodec) Decode(conn Conn) ([]byte, error) {
+	size, buf := conn.ReadN(c.Size)
+	if size < c.Size {
+		return nil, errors.ErrIncompletePacket
+	}
+
+	// Copy out of the ring buffer's memory before ShiftN, which is free to evict or
+	// reuse the region buf currently points into.
+	frame := make([]byte, c.Size)
+	copy(frame, buf)
+	conn.ShiftN(c.Size)
+	return frame, nil
+}
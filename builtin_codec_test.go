@@ -0,0 +1,172 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/zjllib/gnet/errors"
+)
+
+// fakeConn is a minimal Conn standing in for the ring-buffer-backed implementation,
+// just enough to exercise the built-in codecs' Decode logic: ReadN/Read never evict
+// data, ShiftN does, and a buffer reused after ShiftN should not corrupt a frame a
+// codec already returned.
+type fakeConn struct {
+	Conn
+	buf []byte
+}
+
+func (c *fakeConn) Read() []byte { return c.buf }
+
+func (c *fakeConn) ReadN(n int) (int, []byte) {
+	if len(c.buf) < n {
+		return len(c.buf), c.buf
+	}
+	return n, c.buf[:n]
+}
+
+func (c *fakeConn) ShiftN(n int) int {
+	if n > len(c.buf) {
+		n = len(c.buf)
+	}
+	evicted := c.buf[:n]
+	c.buf = c.buf[n:]
+	// Simulate the ring buffer reusing the evicted region for the next read, the way
+	// a real implementation would: if a codec handed out a slice into this memory
+	// without copying it, this will corrupt it.
+	for i := range evicted {
+		evicted[i] = 0xFF
+	}
+	return n
+}
+
+func TestLengthPrefixCodecRoundTrip(t *testing.T) {
+	codec := &LengthPrefixCodec{PrefixBytes: 4, ByteOrder: binary.BigEndian}
+
+	encoded, err := codec.Encode(nil, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+
+	conn := &fakeConn{buf: append(encoded, []byte("tail")...)}
+	frame, err := codec.Decode(conn)
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if string(frame) != "hello" {
+		t.Fatalf("got frame %q, want %q", frame, "hello")
+	}
+	if string(conn.buf) != "tail" {
+		t.Fatalf("Decode consumed the wrong number of bytes, remaining buf is %q", conn.buf)
+	}
+}
+
+func TestLengthPrefixCodecIncomplete(t *testing.T) {
+	codec := &LengthPrefixCodec{PrefixBytes: 2, ByteOrder: binary.BigEndian}
+	conn := &fakeConn{buf: []byte{0x00, 0x05, 'h', 'i'}}
+	if _, err := codec.Decode(conn); err != errors.ErrIncompletePacket {
+		t.Fatalf("got err %v, want ErrIncompletePacket", err)
+	}
+}
+
+func TestDelimiterCodecRoundTrip(t *testing.T) {
+	codec := &DelimiterCodec{Delimiter: []byte("\r\n")}
+	conn := &fakeConn{buf: []byte("first\r\nsecond")}
+
+	frame, err := codec.Decode(conn)
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if string(frame) != "first" {
+		t.Fatalf("got frame %q, want %q", frame, "first")
+	}
+	if string(conn.buf) != "second" {
+		t.Fatalf("unexpected remaining buf %q", conn.buf)
+	}
+}
+
+func TestDelimiterCodecIncomplete(t *testing.T) {
+	codec := &DelimiterCodec{Delimiter: []byte("\n")}
+	conn := &fakeConn{buf: []byte("no delimiter here")}
+	if _, err := codec.Decode(conn); err != errors.ErrIncompletePacket {
+		t.Fatalf("got err %v, want ErrIncompletePacket", err)
+	}
+}
+
+func TestFixedLengthCodecRoundTrip(t *testing.T) {
+	codec := &FixedLengthCodec{Size: 4}
+	conn := &fakeConn{buf: []byte("abcdxyz")}
+
+	frame, err := codec.Decode(conn)
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if string(frame) != "abcd" {
+		t.Fatalf("got frame %q, want %q", frame, "abcd")
+	}
+	if string(conn.buf) != "xyz" {
+		t.Fatalf("unexpected remaining buf %q", conn.buf)
+	}
+}
+
+func TestFixedLengthCodecIncomplete(t *testing.T) {
+	codec := &FixedLengthCodec{Size: 10}
+	conn := &fakeConn{buf: []byte("short")}
+	if _, err := codec.Decode(conn); err != errors.ErrIncompletePacket {
+		t.Fatalf("got err %v, want ErrIncompletePacket", err)
+	}
+}
+
+// TestDecodedFramesSurviveBufferReuse pins down the bug class the fakeConn's ShiftN
+// simulates: a frame returned by Decode must still read correctly even after the
+// connection's buffer has been shifted (and, as a real ring buffer eventually would,
+// overwritten) for the next read.
+func TestDecodedFramesSurviveBufferReuse(t *testing.T) {
+	for name, codec := range map[string]Codec{
+		"length-prefix": &LengthPrefixCodec{PrefixBytes: 2, ByteOrder: binary.BigEndian},
+		"delimiter":     &DelimiterCodec{Delimiter: []byte("\n")},
+		"fixed-length":  &FixedLengthCodec{Size: 5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf []byte
+			switch name {
+			case "length-prefix":
+				buf = []byte{0x00, 0x05, 'h', 'e', 'l', 'l', 'o'}
+			case "delimiter":
+				buf = []byte("hello\n")
+			case "fixed-length":
+				buf = []byte("hello")
+			}
+
+			conn := &fakeConn{buf: buf}
+			frame, err := codec.Decode(conn)
+			if err != nil {
+				t.Fatalf("Decode returned error: %v", err)
+			}
+			if string(frame) != "hello" {
+				t.Fatalf("got frame %q, want %q", frame, "hello")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+// Codec frames application messages onto and off of a Conn's byte stream, so that
+// EventHandler.React deals in whole messages instead of raw, possibly partial, reads.
+//
+// Decode is called with whatever bytes are currently available; returning
+// gnet/errors.ErrIncompletePacket tells the event-loop the buffer doesn't hold a full
+// frame yet, and to leave it untouched until more data arrives. The loop calls Decode
+// again in a tight loop after every successful frame, without returning from the
+// event, until a Decode call either errors or reports ErrIncompletePacket, so a
+// single read that contains several frames is dispatched to React once per frame.
+type Codec interface {
+	// Encode transforms buf, the bytes an EventHandler wants to send on c, into what
+	// should actually be written to the wire (e.g. with a length prefix prepended).
+	Encode(c Conn, buf []byte) ([]byte, error)
+
+	// Decode extracts the next complete frame from c's inbound buffer. It returns
+	// gnet/errors.ErrIncompletePacket if c doesn't yet hold a full frame.
+	Decode(c Conn) (frame []byte, err error)
+}
+
+// WithCodec sets the Codec used to frame inbound messages for React and to encode the
+// out bytes React returns. Data passed to AsyncWrite or SendTo, and OnOpened's out, is
+// written as given, without Encode. Without this option, React receives raw, unframed
+// bytes exactly as they arrived, which is gnet's default behavior.
+func WithCodec(codec Codec) Option {
+	return func(opts *Options) {
+		opts.Codec = codec
+	}
+}
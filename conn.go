@@ -1,6 +1,9 @@
 package gnet
 
-import "net"
+import (
+	"net"
+	"time"
+)
 
 // Conn is a interface of gnet connection.
 type Conn interface {
@@ -52,4 +55,19 @@ type Conn interface {
 
 	// Close closes the current connection.
 	Close() error
+
+	// SetReadDeadline sets a read deadline for this connection: once t passes, it is
+	// closed and OnClosed fires with gnet/errors.ErrReadTimeout, whether or not data
+	// was read in the meantime. A zero value for t clears any existing deadline.
+	SetReadDeadline(t time.Time) error
+
+	// SetWriteDeadline sets a write deadline for this connection: once t passes, it is
+	// closed and OnClosed fires with gnet/errors.ErrWriteTimeout, whether or not data
+	// was written in the meantime. A zero value for t clears any existing deadline.
+	SetWriteDeadline(t time.Time) error
+
+	// SetIdleTimeout closes the connection if no data is read from or written to it
+	// for longer than d, firing OnClosed with gnet/errors.ErrIdleTimeout. d <= 0
+	// disables the idle timeout for this connection.
+	SetIdleTimeout(d time.Duration) error
 }
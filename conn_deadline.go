@@ -0,0 +1,77 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import "time"
+
+// SetReadDeadline implements Conn.SetReadDeadline by arming (or disarming, for a zero
+// t) an entry in the owning event-loop's timing wheel. c.readTimer is kept up to date
+// so a later call replaces the previous deadline in O(1) instead of scanning the
+// wheel for it.
+func (c *conn) SetReadDeadline(t time.Time) error {
+	if t.IsZero() {
+		c.loop.tw.remove(c.readTimer)
+		c.readTimer = nil
+		return nil
+	}
+	c.readTimer = c.loop.tw.add(c.readTimer, c, timerKindRead, t)
+	return nil
+}
+
+// SetWriteDeadline implements Conn.SetWriteDeadline the same way SetReadDeadline
+// implements its read counterpart.
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	if t.IsZero() {
+		c.loop.tw.remove(c.writeTimer)
+		c.writeTimer = nil
+		return nil
+	}
+	c.writeTimer = c.loop.tw.add(c.writeTimer, c, timerKindWrite, t)
+	return nil
+}
+
+// SetIdleTimeout implements Conn.SetIdleTimeout. Unlike the read/write deadlines,
+// which are one-shot, every read or write on c should re-arm its idle timer with a
+// fresh d-from-now deadline; that re-arming happens alongside the connection's
+// existing read/write bookkeeping, not here.
+func (c *conn) SetIdleTimeout(d time.Duration) error {
+	if d <= 0 {
+		c.loop.tw.remove(c.idleTimer)
+		c.idleTimer = nil
+		c.idleTimeout = 0
+		return nil
+	}
+	c.idleTimeout = d
+	c.idleTimer = c.loop.tw.add(c.idleTimer, c, timerKindIdle, time.Now().Add(d))
+	return nil
+}
+
+// bumpIdleTimer re-arms c's idle timer (if one is set) to expire idleTimeout from
+// now. It's called after every successful read on c (by serveConn) and every
+// successful write (by AsyncWrite and SendTo), so a connection that's still active
+// isn't closed as idle.
+func (c *conn) bumpIdleTimer() {
+	if c.idleTimeout <= 0 {
+		return
+	}
+	c.idleTimer = c.loop.tw.add(c.idleTimer, c, timerKindIdle, time.Now().Add(c.idleTimeout))
+}
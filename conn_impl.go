@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// conn is the concrete, net.Conn-backed implementation of the Conn interface used for
+// every stream (tcp/unix) connection. Its inbound buffer is a plain, mutex-guarded
+// byte slice rather than a literal ring buffer; Read/ReadN return copies out of it so
+// callers never alias memory ShiftN is free to reuse.
+type conn struct {
+	loop *eventloop
+	nc   net.Conn
+	ctx  interface{}
+
+	mu      sync.Mutex
+	inbound []byte
+
+	// dispatchMu serializes el.dispatch(c) calls against this connection: c's own
+	// read loop and an explicit Wake call must never run React concurrently.
+	dispatchMu sync.Mutex
+
+	closeOnce sync.Once
+	closeErr  error
+
+	readTimer, writeTimer, idleTimer *timer
+	idleTimeout                      time.Duration
+}
+
+func newConn(nc net.Conn, loop *eventloop) *conn {
+	return &conn{loop: loop, nc: nc}
+}
+
+func (c *conn) Context() interface{}       { return c.ctx }
+func (c *conn) SetContext(ctx interface{}) { c.ctx = ctx }
+func (c *conn) LocalAddr() net.Addr        { return c.nc.LocalAddr() }
+func (c *conn) RemoteAddr() net.Addr       { return c.nc.RemoteAddr() }
+
+func (c *conn) appendInbound(b []byte) {
+	c.mu.Lock()
+	c.inbound = append(c.inbound, b...)
+	c.mu.Unlock()
+}
+
+func (c *conn) Read() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]byte, len(c.inbound))
+	copy(out, c.inbound)
+	return out
+}
+
+func (c *conn) ResetBuffer() {
+	c.mu.Lock()
+	c.inbound = c.inbound[:0]
+	c.mu.Unlock()
+}
+
+func (c *conn) ReadN(n int) (size int, buf []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n > len(c.inbound) {
+		n = len(c.inbound)
+	}
+	out := make([]byte, n)
+	copy(out, c.inbound[:n])
+	return n, out
+}
+
+func (c *conn) ShiftN(n int) (size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n > len(c.inbound) {
+		n = len(c.inbound)
+	}
+	remaining := copy(c.inbound, c.inbound[n:])
+	c.inbound = c.inbound[:remaining]
+	return n
+}
+
+func (c *conn) BufferLength() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.inbound)
+}
+
+// SendTo and AsyncWrite both just write straight through to the underlying socket;
+// gnet doesn't buffer outbound data beyond what net.Conn.Write itself does. A
+// successful write re-arms c's idle timer.
+func (c *conn) SendTo(buf []byte) error {
+	_, err := c.nc.Write(buf)
+	if err == nil {
+		c.bumpIdleTimer()
+	}
+	return err
+}
+
+func (c *conn) AsyncWrite(buf []byte) error {
+	_, err := c.nc.Write(buf)
+	if err == nil {
+		c.bumpIdleTimer()
+	}
+	return err
+}
+
+// Wake re-runs React for c outside of its own read loop, guarded by dispatchMu so it
+// never overlaps a React call already in progress from a just-arrived read.
+func (c *conn) Wake() error {
+	c.dispatchMu.Lock()
+	shouldClose := c.loop.dispatch(c)
+	c.dispatchMu.Unlock()
+	if shouldClose {
+		return c.loop.closeConn(c, nil)
+	}
+	return nil
+}
+
+func (c *conn) Close() error {
+	return c.loop.closeConn(c, nil)
+}
+
+// close is the idempotent core of teardown, shared by closeConn (for whatever reason
+// the connection went away) so the socket is only ever closed once.
+func (c *conn) close(err error) error {
+	var closeErr error
+	c.closeOnce.Do(func() {
+		c.loop.tw.remove(c.readTimer)
+		c.loop.tw.remove(c.writeTimer)
+		c.loop.tw.remove(c.idleTimer)
+		c.closeErr = err
+		closeErr = c.nc.Close()
+	})
+	return closeErr
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package errors contains the sentinel errors that gnet hands back to callers and to
+// EventHandler callbacks such as OnClosed, so they can be distinguished with ==
+// instead of parsing strings.
+package errors
+
+import "errors"
+
+var (
+	// ErrTooManyEventLoopThreads occurs when too many event-loop goroutines have been
+	// locked to OS threads via LockOSThread.
+	ErrTooManyEventLoopThreads = errors.New("too many event-loops under LockOSThread mode")
+
+	// ErrIdleTimeout occurs when a connection is closed because it sat idle (no reads
+	// or writes) for longer than its configured SetIdleTimeout/WithIdleTimeout.
+	ErrIdleTimeout = errors.New("connection closed: idle timeout")
+
+	// ErrReadTimeout occurs when a connection is closed because its SetReadDeadline
+	// deadline elapsed.
+	ErrReadTimeout = errors.New("connection closed: read timeout")
+
+	// ErrWriteTimeout occurs when a connection is closed because its SetWriteDeadline
+	// deadline elapsed.
+	ErrWriteTimeout = errors.New("connection closed: write timeout")
+
+	// ErrIncompletePacket is returned by a Codec's Decode method to indicate that the
+	// inbound buffer doesn't yet contain a full frame. The event-loop leaves the
+	// buffer untouched and waits for more bytes instead of treating it as a decode
+	// failure.
+	ErrIncompletePacket = errors.New("incomplete packet")
+
+	// ErrServerShutdown occurs when a connection is force-closed because
+	// Server.Shutdown's context expired before the connection drained on its own.
+	ErrServerShutdown = errors.New("connection closed: server shutdown")
+)
@@ -0,0 +1,222 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zjllib/gnet/errors"
+)
+
+// eventloop is one of a server's sub-reactors: a pool of connections assigned to it by
+// the LoadBalancer, its own timingWheel for their read/write/idle deadlines, and a
+// goroutine that drives that wheel and any jobs queued onto it via queueJob.
+//
+// Every connection owned by an eventloop runs its own read loop on its own goroutine
+// (see serveConn); dispatchMu on conn serializes that goroutine against a concurrent
+// Wake call for the same connection, since React must never run concurrently with
+// itself for one Conn.
+type eventloop struct {
+	idx     int
+	handler EventHandler
+	codec   Codec
+
+	connCount int32
+	tw        *timingWheel
+
+	mu    sync.Mutex
+	conns map[*conn]struct{}
+
+	asyncJobQueue chan func()
+	done          chan struct{}
+}
+
+func newEventLoop(idx int, handler EventHandler, codec Codec) *eventloop {
+	return &eventloop{
+		idx:           idx,
+		handler:       handler,
+		codec:         codec,
+		tw:            newTimingWheel(),
+		conns:         make(map[*conn]struct{}),
+		asyncJobQueue: make(chan func(), 128),
+		done:          make(chan struct{}),
+	}
+}
+
+// run is el's own goroutine: it advances el's timing wheel and executes anything
+// queued via queueJob until stop is called. Connection I/O itself happens on each
+// connection's own goroutine (see serveConn), not here; a single goroutine can't
+// multiplex many blocking net.Conn reads without an OS-level poller.
+func (el *eventloop) run() {
+	ticker := time.NewTicker(timingWheelTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-el.done:
+			return
+		case job := <-el.asyncJobQueue:
+			job()
+		case now := <-ticker.C:
+			el.tickTimingWheel(now)
+		}
+	}
+}
+
+func (el *eventloop) stop() {
+	close(el.done)
+}
+
+// queueJob hands job to el's own goroutine, falling back to a no-op if el has already
+// stopped.
+func (el *eventloop) queueJob(job func()) {
+	select {
+	case el.asyncJobQueue <- job:
+	case <-el.done:
+	}
+}
+
+// serveConn runs c's entire lifecycle on the calling goroutine: OnOpened, a blocking
+// read loop that feeds every chunk of inbound data through dispatch, and OnClosed once
+// the peer goes away or a handler callback asks to close.
+func (el *eventloop) serveConn(c *conn, idleTimeout time.Duration) {
+	el.mu.Lock()
+	el.conns[c] = struct{}{}
+	el.mu.Unlock()
+	atomic.AddInt32(&el.connCount, 1)
+
+	if idleTimeout > 0 {
+		c.SetIdleTimeout(idleTimeout)
+	}
+
+	out, action := el.handler.OnOpened(c)
+	if len(out) > 0 {
+		c.AsyncWrite(out)
+	}
+	if action != None {
+		el.closeConn(c, nil)
+		return
+	}
+
+	scratch := make([]byte, 64*1024)
+	for {
+		n, err := c.nc.Read(scratch)
+		if n > 0 {
+			c.appendInbound(scratch[:n])
+			c.bumpIdleTimer()
+
+			c.dispatchMu.Lock()
+			shouldClose := el.dispatch(c)
+			c.dispatchMu.Unlock()
+
+			if shouldClose {
+				el.closeConn(c, nil)
+				return
+			}
+		}
+		if err != nil {
+			el.closeConn(c, err)
+			return
+		}
+	}
+}
+
+// dispatch drains whatever c.inbound currently holds, feeding complete messages to
+// el.handler.React, and reports whether the connection should now be closed.
+//
+// Without a Codec, React receives the entire current buffer as a single raw frame and
+// el evicts all of it immediately afterward: raw mode has no notion of a partial
+// frame, so a handler that needs to reassemble messages spanning multiple reads (see
+// the gnet/http package) must buffer the unconsumed remainder itself.
+//
+// With a Codec, el instead calls Decode in a tight loop, dispatching one frame per
+// call to React until Decode reports ErrIncompletePacket (nothing left to do until
+// more bytes arrive) or a hard error (malformed frame, close the connection). Decode
+// and the codec's own ShiftN calls manage eviction in that mode, so multiple frames in
+// one read are each dispatched before dispatch returns.
+func (el *eventloop) dispatch(c *conn) (shouldClose bool) {
+	if el.codec == nil {
+		frame := c.Read()
+		out, action := el.handler.React(frame, c)
+		c.ResetBuffer()
+		if len(out) > 0 {
+			c.AsyncWrite(out)
+		}
+		return action != None
+	}
+
+	for {
+		frame, err := el.codec.Decode(c)
+		if err == errors.ErrIncompletePacket {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+
+		out, action := el.handler.React(frame, c)
+		if len(out) > 0 {
+			if encoded, eerr := el.codec.Encode(c, out); eerr == nil {
+				c.AsyncWrite(encoded)
+			}
+		}
+		if action != None {
+			return true
+		}
+	}
+}
+
+// closeConn idempotently tears down c: closing its socket, unlinking its timers,
+// removing it from el's bookkeeping and firing OnClosed exactly once no matter how
+// many goroutines (c's own read loop, a timing-wheel expiry, an explicit Close call)
+// race to close it.
+func (el *eventloop) closeConn(c *conn, err error) error {
+	el.mu.Lock()
+	_, existed := el.conns[c]
+	delete(el.conns, c)
+	el.mu.Unlock()
+
+	closeErr := c.close(err)
+	if existed {
+		atomic.AddInt32(&el.connCount, -1)
+		if el.handler != nil {
+			el.handler.OnClosed(c, err)
+		}
+	}
+	return closeErr
+}
+
+// closeAllConns force-closes every connection currently registered on el, used by
+// Server.Shutdown once its drain deadline has passed.
+func (el *eventloop) closeAllConns(err error) {
+	el.mu.Lock()
+	conns := make([]*conn, 0, len(el.conns))
+	for c := range el.conns {
+		conns = append(conns, c)
+	}
+	el.mu.Unlock()
+
+	for _, c := range conns {
+		el.closeConn(c, err)
+	}
+}
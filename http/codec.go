@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// errIncompleteRequest is returned by codec.decode when frame doesn't yet hold a
+// full request; the caller should stop processing and wait for more bytes, the same
+// way a Codec's Decode is expected to signal ErrIncompletePacket elsewhere in gnet.
+var errIncompleteRequest = errors.New("http: incomplete request")
+
+// codec incrementally parses HTTP/1.1 requests out of the bytes handed to it by
+// React. One is allocated per Conn in OnOpened and stashed in Conn.Context.
+//
+// pending accumulates bytes across React calls: gnet's raw event loop hands React the
+// currently-buffered data and evicts all of it once React returns, so a request that
+// arrives split across multiple reads has to be reassembled here rather than relying
+// on the connection's own buffer to retain it.
+type codec struct {
+	pending []byte
+}
+
+func newCodec() *codec { return &codec{} }
+
+// decode parses the first complete request out of frame and returns how many bytes
+// it consumed. Pipelined requests are handled by the caller repeatedly calling decode
+// on the remaining, unconsumed tail.
+func (c *codec) decode(frame []byte) (*Request, int, error) {
+	headerEnd := bytes.Index(frame, []byte("\r\n\r\n"))
+	if headerEnd == -1 {
+		return nil, 0, errIncompleteRequest
+	}
+
+	lines := strings.Split(string(frame[:headerEnd]), "\r\n")
+	if len(lines) == 0 {
+		return nil, 0, errors.New("http: empty request")
+	}
+
+	requestLine := strings.SplitN(lines[0], " ", 3)
+	if len(requestLine) != 3 {
+		return nil, 0, errors.New("http: malformed request line")
+	}
+
+	req := &Request{
+		Method: requestLine[0],
+		Proto:  requestLine[2],
+		Header: make(Header),
+	}
+	req.Path, req.Query = splitPathQuery(requestLine[1])
+
+	for _, line := range lines[1:] {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		req.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+
+	req.keepAlive = req.Proto == "HTTP/1.1" && !strings.EqualFold(req.Header.Get("Connection"), "close")
+	req.expectContinue = strings.EqualFold(req.Header.Get("Expect"), "100-continue")
+
+	bodyStart := headerEnd + len("\r\n\r\n")
+
+	if strings.EqualFold(req.Header.Get("Transfer-Encoding"), "chunked") {
+		body, n, err := decodeChunked(frame[bodyStart:])
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Body = body
+		return req, bodyStart + n, nil
+	}
+
+	contentLength := 0
+	if cl := req.Header.Get("Content-Length"); cl != "" {
+		var err error
+		if contentLength, err = strconv.Atoi(cl); err != nil {
+			return nil, 0, errors.New("http: malformed Content-Length")
+		}
+	}
+
+	if len(frame)-bodyStart < contentLength {
+		return nil, 0, errIncompleteRequest
+	}
+
+	// Copy the body out of frame before returning it: frame aliases the connection's
+	// pending-bytes buffer, which the caller trims and appends to on every subsequent
+	// call, so a slice into it would be corrupted out from under any handler that
+	// retains req.Body past this call.
+	body := make([]byte, contentLength)
+	copy(body, frame[bodyStart:bodyStart+contentLength])
+	req.Body = body
+	return req, bodyStart + contentLength, nil
+}
+
+// decodeChunked reassembles a chunked-transfer-encoded body, returning the decoded
+// body and the number of raw bytes consumed (including the terminating zero-length
+// chunk and its trailing CRLF). It returns errIncompleteRequest if the final chunk
+// hasn't arrived yet.
+func decodeChunked(buf []byte) (body []byte, consumed int, err error) {
+	for {
+		lineEnd := bytes.Index(buf[consumed:], []byte("\r\n"))
+		if lineEnd == -1 {
+			return nil, 0, errIncompleteRequest
+		}
+		sizeLine := string(buf[consumed : consumed+lineEnd])
+		consumed += lineEnd + 2
+
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeLine), 16, 64)
+		if err != nil {
+			return nil, 0, errors.New("http: malformed chunk size")
+		}
+		if size == 0 {
+			if len(buf) < consumed+2 {
+				return nil, 0, errIncompleteRequest
+			}
+			consumed += 2 // trailing CRLF after the terminating 0-size chunk
+			return body, consumed, nil
+		}
+
+		if int64(len(buf)-consumed) < size+2 {
+			return nil, 0, errIncompleteRequest
+		}
+		body = append(body, buf[consumed:consumed+int(size)]...)
+		consumed += int(size) + 2 // chunk data plus its trailing CRLF
+	}
+}
+
+func splitPathQuery(target string) (path, query string) {
+	if i := strings.IndexByte(target, '?'); i != -1 {
+		return target[:i], target[i+1:]
+	}
+	return target, ""
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package http
+
+import "testing"
+
+func TestCodecDecodeRequestLine(t *testing.T) {
+	c := newCodec()
+	raw := "GET /foo?a=1 HTTP/1.1\r\nHost: example.com\r\nContent-Length: 0\r\n\r\n"
+
+	req, n, err := c.decode([]byte(raw))
+	if err != nil {
+		t.Fatalf("decode returned error: %v", err)
+	}
+	if n != len(raw) {
+		t.Fatalf("consumed %d bytes, want %d", n, len(raw))
+	}
+	if req.Method != "GET" || req.Path != "/foo" || req.Query != "a=1" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if req.Header.Get("Host") != "example.com" {
+		t.Fatalf("header not parsed: %+v", req.Header)
+	}
+	if !req.keepAlive {
+		t.Fatal("HTTP/1.1 request without Connection: close should be keep-alive")
+	}
+}
+
+func TestCodecDecodeIncompleteHeaders(t *testing.T) {
+	c := newCodec()
+	_, _, err := c.decode([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n"))
+	if err != errIncompleteRequest {
+		t.Fatalf("got err %v, want errIncompleteRequest", err)
+	}
+}
+
+func TestCodecDecodeWaitsForBody(t *testing.T) {
+	c := newCodec()
+	raw := "POST /submit HTTP/1.1\r\nContent-Length: 5\r\n\r\nhel"
+	_, _, err := c.decode([]byte(raw))
+	if err != errIncompleteRequest {
+		t.Fatalf("got err %v, want errIncompleteRequest", err)
+	}
+}
+
+func TestCodecDecodeConnectionClose(t *testing.T) {
+	c := newCodec()
+	raw := "GET / HTTP/1.1\r\nConnection: close\r\n\r\n"
+	req, _, err := c.decode([]byte(raw))
+	if err != nil {
+		t.Fatalf("decode returned error: %v", err)
+	}
+	if req.keepAlive {
+		t.Fatal("Connection: close should disable keep-alive")
+	}
+}
+
+func TestCodecDecodePipelinedRequests(t *testing.T) {
+	c := newCodec()
+	raw := "GET /a HTTP/1.1\r\n\r\nGET /b HTTP/1.1\r\n\r\n"
+
+	req1, n1, err := c.decode([]byte(raw))
+	if err != nil {
+		t.Fatalf("first decode returned error: %v", err)
+	}
+	if req1.Path != "/a" {
+		t.Fatalf("got path %q, want /a", req1.Path)
+	}
+
+	req2, n2, err := c.decode([]byte(raw)[n1:])
+	if err != nil {
+		t.Fatalf("second decode returned error: %v", err)
+	}
+	if req2.Path != "/b" {
+		t.Fatalf("got path %q, want /b", req2.Path)
+	}
+	if n1+n2 != len(raw) {
+		t.Fatalf("consumed %d bytes across both requests, want %d", n1+n2, len(raw))
+	}
+}
+
+func TestDecodeChunkedReassemblesBody(t *testing.T) {
+	raw := "4\r\nWiki\r\n5\r\npedia\r\n0\r\n\r\n"
+	body, n, err := decodeChunked([]byte(raw))
+	if err != nil {
+		t.Fatalf("decodeChunked returned error: %v", err)
+	}
+	if string(body) != "Wikipedia" {
+		t.Fatalf("got body %q, want %q", body, "Wikipedia")
+	}
+	if n != len(raw) {
+		t.Fatalf("consumed %d bytes, want %d", n, len(raw))
+	}
+}
+
+func TestDecodeChunkedIncomplete(t *testing.T) {
+	raw := "4\r\nWik"
+	_, _, err := decodeChunked([]byte(raw))
+	if err != errIncompleteRequest {
+		t.Fatalf("got err %v, want errIncompleteRequest", err)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package http provides a small HTTP/1.1 codec and router layered directly on top of
+// gnet's EventHandler/Conn, for users who want an HTTP service without hand-rolling a
+// parser on top of the raw-bytes React callback.
+package http
+
+import (
+	"github.com/zjllib/gnet"
+)
+
+// Handler responds to an HTTP request, in the same shape as net/http.Handler so that
+// existing handler functions and middleware are easy to port.
+type Handler interface {
+	ServeHTTP(w ResponseWriter, r *Request)
+}
+
+// HandlerFunc adapts an ordinary function to a Handler.
+type HandlerFunc func(w ResponseWriter, r *Request)
+
+// ServeHTTP calls f(w, r).
+func (f HandlerFunc) ServeHTTP(w ResponseWriter, r *Request) { f(w, r) }
+
+// Serve starts an HTTP server on addr, dispatching every parsed request to mux. addr
+// follows the same scheme-prefixed format as gnet.Serve (e.g. "tcp://:8080"); opts are
+// passed straight through to the underlying gnet.Serve call.
+func Serve(addr string, mux *ServeMux, opts ...gnet.Option) error {
+	hs := &httpServer{mux: mux}
+	return gnet.Serve(hs, addr, opts...)
+}
+
+// httpServer is the EventHandler that drives mux, one codec instance per connection.
+type httpServer struct {
+	*gnet.EventServer
+	mux *ServeMux
+}
+
+func (hs *httpServer) OnOpened(c gnet.Conn) (out []byte, action gnet.Action) {
+	c.SetContext(newCodec())
+	return
+}
+
+// React is called with whatever bytes gnet's raw (no-Codec) event loop currently has
+// buffered for this connection; since that loop evicts the entire frame the instant
+// React returns (raw mode has no notion of a partial frame), the codec can't rely on
+// unconsumed bytes surviving in c's own buffer until the next call. Instead it
+// appends frame onto its own pending buffer, decodes everything it can out of that,
+// and keeps only the undecodable tail (an in-flight request whose bytes haven't all
+// arrived yet) for the next React call.
+func (hs *httpServer) React(frame []byte, c gnet.Conn) (out []byte, action gnet.Action) {
+	cd, _ := c.Context().(*codec)
+	if cd == nil {
+		cd = newCodec()
+		c.SetContext(cd)
+	}
+	cd.pending = append(cd.pending, frame...)
+
+	buf := &responseBuffer{}
+	for {
+		req, n, err := cd.decode(cd.pending)
+		if err == errIncompleteRequest {
+			break
+		}
+		if err != nil {
+			buf.writeStatus(StatusBadRequest)
+			action = gnet.Close
+			cd.pending = nil
+			break
+		}
+
+		w := &responseWriter{buf: buf, req: req, conn: c}
+		if req.expectContinue {
+			buf.writeContinue()
+		}
+		hs.mux.ServeHTTP(w, req)
+		w.finish()
+
+		cd.pending = cd.pending[n:]
+
+		if !req.keepAlive {
+			action = gnet.Close
+			break
+		}
+		if len(cd.pending) == 0 {
+			break
+		}
+	}
+
+	out = buf.Bytes()
+	return
+}
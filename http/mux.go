@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package http
+
+import "sync"
+
+// ServeMux is a lightweight, net/http.ServeMux-compatible request router: exact
+// matches take priority, and a pattern ending in "/" matches any path with that
+// prefix.
+type ServeMux struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewServeMux allocates a new, empty ServeMux.
+func NewServeMux() *ServeMux {
+	return &ServeMux{handlers: make(map[string]Handler)}
+}
+
+// Handle registers handler for the given pattern.
+func (mux *ServeMux) Handle(pattern string, handler Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.handlers[pattern] = handler
+}
+
+// HandleFunc registers handler (as a HandlerFunc) for the given pattern.
+func (mux *ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Request)) {
+	mux.Handle(pattern, HandlerFunc(handler))
+}
+
+// ServeHTTP dispatches r to the most specific registered pattern that matches its
+// path, or replies 404 if nothing matches.
+func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
+	h := mux.handler(r.Path)
+	if h == nil {
+		w.WriteHeader(StatusNotFound)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+func (mux *ServeMux) handler(path string) Handler {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	if h, ok := mux.handlers[path]; ok {
+		return h
+	}
+
+	var best string
+	var bestHandler Handler
+	for pattern, h := range mux.handlers {
+		if len(pattern) == 0 || pattern[len(pattern)-1] != '/' {
+			continue
+		}
+		if len(path) >= len(pattern) && path[:len(pattern)] == pattern && len(pattern) > len(best) {
+			best, bestHandler = pattern, h
+		}
+	}
+	return bestHandler
+}
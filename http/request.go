@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package http
+
+// Request is a parsed HTTP/1.1 request. It is decoded out of the bytes the
+// connection's codec has accumulated across React calls; Body, if present, is a copy
+// that has already been fully
+// buffered (dechunked, if the request used chunked transfer encoding) by the time
+// the handler sees it.
+type Request struct {
+	Method  string
+	Path    string
+	Query   string
+	Proto   string
+	Header  Header
+	Body    []byte
+
+	keepAlive      bool
+	expectContinue bool
+}
+
+// Header is a case-insensitive HTTP header map, keyed by canonical
+// (e.g. "Content-Type") form.
+type Header map[string][]string
+
+// Get returns the first value associated with key, or "" if there isn't one.
+func (h Header) Get(key string) string {
+	v := h[canonicalHeaderKey(key)]
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+// Set replaces any existing values for key with value.
+func (h Header) Set(key, value string) {
+	h[canonicalHeaderKey(key)] = []string{value}
+}
+
+// Add appends value to any existing values for key.
+func (h Header) Add(key, value string) {
+	k := canonicalHeaderKey(key)
+	h[k] = append(h[k], value)
+}
+
+func canonicalHeaderKey(key string) string {
+	b := []byte(key)
+	upper := true
+	for i, c := range b {
+		switch {
+		case upper && 'a' <= c && c <= 'z':
+			b[i] = c - ('a' - 'A')
+			upper = false
+		case !upper && 'A' <= c && c <= 'Z':
+			b[i] = c + ('a' - 'A')
+			upper = false
+		default:
+			upper = c == '-'
+		}
+	}
+	return string(b)
+}
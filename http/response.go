@@ -0,0 +1,143 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package http
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/zjllib/gnet"
+)
+
+// Status codes used by this package; it's not meant to be an exhaustive list, only
+// the ones gnet/http itself needs to write.
+const (
+	StatusContinue   = 100
+	StatusOK         = 200
+	StatusBadRequest = 400
+	StatusNotFound   = 404
+)
+
+var statusText = map[int]string{
+	StatusContinue:   "Continue",
+	StatusOK:         "OK",
+	StatusBadRequest: "Bad Request",
+	StatusNotFound:   "Not Found",
+}
+
+// ResponseWriter is implemented by the object passed to a Handler's ServeHTTP method,
+// mirroring net/http.ResponseWriter closely enough that existing handlers port over
+// with minimal changes.
+type ResponseWriter interface {
+	// Header returns the header map that will be sent with WriteHeader.
+	Header() Header
+
+	// Write appends b to the response body, implicitly calling WriteHeader(StatusOK)
+	// first if it hasn't been called yet.
+	Write(b []byte) (int, error)
+
+	// WriteHeader sends the response status line and header. It must be called at
+	// most once, and before any call to Write.
+	WriteHeader(statusCode int)
+}
+
+// responseWriter buffers a single response's output; nothing is flushed to the
+// connection until the codec has finished processing every pipelined request in the
+// current React call, so that React's one AsyncWrite/out assignment covers all of
+// them in order.
+type responseWriter struct {
+	buf         *responseBuffer
+	req         *Request
+	conn        gnet.Conn
+	header      Header
+	wroteHeader bool
+	status      int
+	body        []byte
+}
+
+func (w *responseWriter) Header() Header {
+	if w.header == nil {
+		w.header = make(Header)
+	}
+	return w.header
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	w.body = append(w.body, b...)
+	return len(b), nil
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = statusCode
+}
+
+func (w *responseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if w.header == nil {
+		w.header = make(Header)
+	}
+	w.header.Set("Content-Length", strconv.Itoa(len(w.body)))
+	if w.req != nil && !w.req.keepAlive {
+		w.header.Set("Connection", "close")
+	}
+	w.buf.writeResponse(w.status, w.header, w.body)
+}
+
+// responseBuffer accumulates the bytes for every response produced while draining a
+// single React call, across however many pipelined requests it contained.
+type responseBuffer struct {
+	b []byte
+}
+
+func (rb *responseBuffer) Bytes() []byte { return rb.b }
+
+func (rb *responseBuffer) writeContinue() {
+	rb.b = append(rb.b, "HTTP/1.1 100 Continue\r\n\r\n"...)
+}
+
+func (rb *responseBuffer) writeStatus(status int) {
+	rb.writeResponse(status, nil, nil)
+}
+
+func (rb *responseBuffer) writeResponse(status int, header Header, body []byte) {
+	text := statusText[status]
+	if text == "" {
+		text = "Status"
+	}
+	rb.b = append(rb.b, fmt.Sprintf("HTTP/1.1 %d %s\r\n", status, text)...)
+	for k, vs := range header {
+		for _, v := range vs {
+			rb.b = append(rb.b, fmt.Sprintf("%s: %s\r\n", k, v)...)
+		}
+	}
+	rb.b = append(rb.b, "\r\n"...)
+	rb.b = append(rb.b, body...)
+}
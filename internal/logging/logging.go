@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package logging provides the pluggable logger gnet's core uses for its own
+// diagnostics (the WithLogger option swaps DefaultLogger out for a caller-supplied
+// implementation).
+package logging
+
+import (
+	"log"
+	"os"
+)
+
+// Logger is the logging interface gnet's core depends on. It's deliberately tiny so
+// that wrapping almost any existing logging library (zap, logrus, the standard
+// library's log.Logger, ...) is a few lines of adapter code.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+}
+
+type stdLogger struct {
+	*log.Logger
+}
+
+func (l *stdLogger) Errorf(format string, args ...interface{}) {
+	l.Printf(format, args...)
+}
+
+// DefaultLogger is used for every gnet diagnostic message until Serve is called with
+// a WithLogger option naming a different one.
+var DefaultLogger Logger = &stdLogger{log.New(os.Stderr, "gnet: ", log.LstdFlags)}
+
+// Cleanup is called by Serve via a deferred call once it returns. It does nothing:
+// neither the standard logger nor a WithLogger implementation is flushed or released
+// by it.
+func Cleanup() {}
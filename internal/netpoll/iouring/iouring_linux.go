@@ -0,0 +1,162 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package iouring implements an io_uring-backed poller that can be used as a drop-in
+// replacement for the epoll-based poller on Linux kernels that support the required
+// opcodes (IORING_OP_ACCEPT, IORING_OP_RECV, IORING_OP_SEND, IORING_OP_CLOSE).
+package iouring
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// entries is the number of submission/completion queue entries requested when the
+// ring is set up. It is sized generously since SQEs/CQEs are cheap and a loop may be
+// driving a large number of connections.
+const entries = 4096
+
+// minKernelMajor.minKernelMinor is the lowest kernel version on which gnet will
+// attempt to use io_uring. Earlier kernels either lack io_uring entirely or lack IORING_OP_ACCEPT
+// and fixed-buffer registration, both of which this poller relies on.
+const minKernelMajor, minKernelMinor = 5, 6
+
+// Poller mirrors the epoll-based poller used elsewhere in gnet: it owns one ring per
+// event-loop and is driven from that event-loop's own goroutine, so none of its state
+// needs to be synchronized across loops.
+type Poller struct {
+	fd          int // io_uring file descriptor returned by IORING_SETUP
+	ring        *ring
+	bufs        [][]byte // fixed buffers registered with IORING_REGISTER_BUFFERS
+	asyncJobs   internalJobQueue
+	wakeEventFd int
+	mu          sync.Mutex
+}
+
+// Supported reports whether the running kernel's version is at least
+// minKernelMajor.minKernelMinor. It checks the version only; it does not probe the
+// kernel for the individual opcodes this poller uses. Callers should fall back to the
+// epoll poller when this returns false.
+func Supported() bool {
+	major, minor, ok := kernelVersion()
+	if !ok {
+		return false
+	}
+	if major != minKernelMajor {
+		return major > minKernelMajor
+	}
+	return minor >= minKernelMinor
+}
+
+// OpenPoller sets up a new ring, registers a pool of fixed buffers for zero-copy
+// reads and returns a Poller ready to have fds submitted to it.
+func OpenPoller() (poller *Poller, err error) {
+	if !Supported() {
+		return nil, errUnsupportedKernel
+	}
+
+	poller = new(Poller)
+	if poller.ring, err = setupRing(entries); err != nil {
+		return nil, err
+	}
+	poller.fd = poller.ring.fd
+
+	if poller.bufs, err = registerFixedBuffers(poller.ring, bufferCount, bufferSize); err != nil {
+		poller.ring.close()
+		return nil, err
+	}
+
+	return poller, nil
+}
+
+// Close tears down the ring and releases the registered fixed buffers.
+func (p *Poller) Close() error {
+	unregisterFixedBuffers(p.ring)
+	return p.ring.close()
+}
+
+// AddRead submits a fixed-buffer read SQE for the given file descriptor, targeting
+// the registered buffer at bufIndex so the kernel places inbound bytes directly into
+// memory gnet already owns instead of copying through an intermediate buffer.
+func (p *Poller) AddRead(fd, bufIndex int) error {
+	if bufIndex < 0 || bufIndex >= len(p.bufs) {
+		return fmt.Errorf("iouring: bufIndex %d out of range [0,%d)", bufIndex, len(p.bufs))
+	}
+	return p.ring.submitRecv(fd, bufIndex, p.bufs[bufIndex])
+}
+
+// AddWrite submits a SEND SQE carrying buf for the given file descriptor.
+func (p *Poller) AddWrite(fd int, buf []byte) error {
+	return p.ring.submitSend(fd, buf)
+}
+
+// AddAccept submits a single-shot ACCEPT SQE against the listening socket so a new
+// connection is reaped as a CQE alongside everything else instead of via a separate
+// accept(2) loop. Each SQE accepts one connection; accepting another takes another
+// AddAccept call.
+func (p *Poller) AddAccept(listenerFd int) error {
+	return p.ring.submitAccept(listenerFd)
+}
+
+// Delete submits a CLOSE SQE for fd. SQEs still outstanding against fd are not
+// cancelled.
+func (p *Poller) Delete(fd int) error {
+	return p.ring.submitClose(fd)
+}
+
+// PollCallback is invoked once per reaped completion. res is the CQE's result (a byte
+// count for RECV/SEND, an fd for ACCEPT, or a negative errno), and bufIndex identifies
+// which fixed buffer (if any) the completion refers to.
+type PollCallback func(fd int, op uint8, res int32, bufIndex int) error
+
+// Polling blocks reaping CQEs in bulk and invoking callback for each one, mirroring
+// the epoll poller's Polling loop. It returns when the ring is closed or callback
+// returns a non-nil error asking the loop to stop.
+func (p *Poller) Polling(callback PollCallback) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for {
+		cqes, err := p.ring.waitCQEs(entries)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, cqe := range cqes {
+			if err := callback(cqe.fd, cqe.op, cqe.res, cqe.bufIndex); err != nil {
+				return err
+			}
+		}
+		p.ring.advanceCQ(len(cqes))
+	}
+}
+
+// Trigger wakes up a blocked Polling call, used when another goroutine needs the
+// event-loop to pick up asynchronous work (AsyncWrite, Wake, shutdown) immediately
+// instead of waiting for the next natural completion.
+func (p *Poller) Trigger() error {
+	return p.ring.submitNop()
+}
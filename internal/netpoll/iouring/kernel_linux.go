@@ -0,0 +1,78 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package iouring
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernelVersion parses the major/minor version out of uname(2)'s release string
+// (e.g. "5.15.0-land1-generic" -> 5, 15). ok is false if the release string couldn't
+// be parsed, in which case callers should treat io_uring as unsupported.
+func kernelVersion() (major, minor int, ok bool) {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return 0, 0, false
+	}
+
+	release := charsToString(uts.Release[:])
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(trimNonDigits(parts[1]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+func trimNonDigits(s string) string {
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func charsToString(ca []byte) string {
+	for i, c := range ca {
+		if c == 0 {
+			return string(ca[:i])
+		}
+	}
+	return string(ca)
+}
+
+// errUnsupportedKernel is returned by OpenPoller when Supported() is false.
+var errUnsupportedKernel = fmt.Errorf("io_uring: kernel does not support the required opcodes, need >= %d.%d",
+	minKernelMajor, minKernelMinor)
@@ -0,0 +1,44 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+// PollerType names a netpoll backend for an event-loop.
+type PollerType int
+
+const (
+	// PollerEpoll names the epoll backend. It is the zero value of PollerType.
+	PollerEpoll PollerType = iota
+
+	// PollerIOUring names the io_uring backend in internal/netpoll/iouring, which
+	// needs Linux >= 5.6.
+	PollerIOUring
+)
+
+// WithIOPoller sets Options.IOPoller. Serve does not read that field: whichever
+// PollerType is set, every event-loop serves its connections with blocking net.Conn
+// reads on per-connection goroutines, and Serve does not use the
+// internal/netpoll/iouring package.
+func WithIOPoller(poller PollerType) Option {
+	return func(opts *Options) {
+		opts.IOPoller = poller
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"net"
+	"strings"
+)
+
+// listener wraps whatever the server is actually bound to: a stream net.Listener for
+// tcp/unix, or a net.PacketConn for udp.
+type listener struct {
+	ln net.Listener
+	pc net.PacketConn
+
+	network string
+	addr    string
+}
+
+// initListener binds network/addr, matching the schemes documented on Serve.
+// reusePort is accepted for interface symmetry with the rest of Options; this pure-Go
+// listener doesn't set SO_REUSEPORT itself; it's left at the OS default.
+func initListener(network, addr string, _ bool) (*listener, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+		pc, err := net.ListenPacket(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &listener{pc: pc, network: network, addr: addr}, nil
+	default:
+		ln, err := net.Listen(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &listener{ln: ln, network: network, addr: addr}, nil
+	}
+}
+
+// Addr returns the address actually bound to, which for addr:0 resolves the OS-chosen
+// port.
+func (l *listener) Addr() net.Addr {
+	if l.ln != nil {
+		return l.ln.Addr()
+	}
+	return l.pc.LocalAddr()
+}
+
+func (l *listener) close() error {
+	if l.ln != nil {
+		return l.ln.Close()
+	}
+	return l.pc.Close()
+}
+
+// parseProtoAddr splits a scheme-prefixed address like "tcp://127.0.0.1:9000" into its
+// network and address parts; an address with no scheme is assumed to be "tcp", per
+// Serve's doc comment.
+func parseProtoAddr(protoAddr string) (network, addr string) {
+	network = "tcp"
+	addr = protoAddr
+	if idx := strings.Index(protoAddr, "://"); idx != -1 {
+		network = protoAddr[:idx]
+		addr = protoAddr[idx+len("://"):]
+	}
+	return
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"hash/maphash"
+	"net"
+	"sync/atomic"
+)
+
+// LoadBalancer decides which event-loop a newly-accepted connection is handed to. A
+// server has exactly one LoadBalancer, shared by all of its sub-reactors.
+type LoadBalancer interface {
+	// Register adds an event-loop to the pool the load balancer chooses from. It is
+	// called once per sub-reactor while the server is starting up.
+	Register(el *eventloop)
+
+	// Next picks the event-loop that should own the connection being accepted from
+	// addr. addr may be nil (e.g. for listeners that don't expose it cheaply), in
+	// which case implementations that don't need it (round-robin, least-connections)
+	// should ignore it.
+	Next(addr net.Addr) *eventloop
+
+	// Iterate walks every registered event-loop, stopping early if callback returns
+	// false. It replaces direct iteration over subEventLoopSet so callers such as
+	// Server.CountConnections don't need to know which balancing strategy is active.
+	Iterate(callback func(int, *eventloop) bool)
+}
+
+// roundRobinLoadBalancer cycles through event-loops in registration order. This is
+// gnet's original, default behavior.
+type roundRobinLoadBalancer struct {
+	nextLoopIndex int
+	eventLoops    []*eventloop
+}
+
+func newRoundRobinLoadBalancer() *roundRobinLoadBalancer {
+	return new(roundRobinLoadBalancer)
+}
+
+func (lb *roundRobinLoadBalancer) Register(el *eventloop) {
+	lb.eventLoops = append(lb.eventLoops, el)
+}
+
+func (lb *roundRobinLoadBalancer) Next(_ net.Addr) (el *eventloop) {
+	el = lb.eventLoops[lb.nextLoopIndex]
+	if lb.nextLoopIndex++; lb.nextLoopIndex >= len(lb.eventLoops) {
+		lb.nextLoopIndex = 0
+	}
+	return
+}
+
+func (lb *roundRobinLoadBalancer) Iterate(callback func(int, *eventloop) bool) {
+	for i, el := range lb.eventLoops {
+		if !callback(i, el) {
+			break
+		}
+	}
+}
+
+// leastConnectionsLoadBalancer always hands a new connection to whichever registered
+// event-loop currently owns the fewest connections.
+type leastConnectionsLoadBalancer struct {
+	eventLoops []*eventloop
+}
+
+func newLeastConnectionsLoadBalancer() *leastConnectionsLoadBalancer {
+	return new(leastConnectionsLoadBalancer)
+}
+
+func (lb *leastConnectionsLoadBalancer) Register(el *eventloop) {
+	lb.eventLoops = append(lb.eventLoops, el)
+}
+
+func (lb *leastConnectionsLoadBalancer) Next(_ net.Addr) (el *eventloop) {
+	el = lb.eventLoops[0]
+	min := atomic.LoadInt32(&el.connCount)
+	for _, candidate := range lb.eventLoops[1:] {
+		if n := atomic.LoadInt32(&candidate.connCount); n < min {
+			el, min = candidate, n
+		}
+	}
+	return
+}
+
+func (lb *leastConnectionsLoadBalancer) Iterate(callback func(int, *eventloop) bool) {
+	for i, el := range lb.eventLoops {
+		if !callback(i, el) {
+			break
+		}
+	}
+}
+
+// sourceAddrHashLoadBalancer consistently hashes a connection's remote host (its IP,
+// with the port left out) onto one of the registered event-loops, so repeat
+// connections from the same client land on the same loop. This is useful for stateful protocols that keep per-client state
+// in the loop or the EventHandler rather than on Conn alone.
+type sourceAddrHashLoadBalancer struct {
+	seed       maphash.Seed
+	eventLoops []*eventloop
+}
+
+func newSourceAddrHashLoadBalancer() *sourceAddrHashLoadBalancer {
+	return &sourceAddrHashLoadBalancer{seed: maphash.MakeSeed()}
+}
+
+func (lb *sourceAddrHashLoadBalancer) Register(el *eventloop) {
+	lb.eventLoops = append(lb.eventLoops, el)
+}
+
+func (lb *sourceAddrHashLoadBalancer) Next(addr net.Addr) (el *eventloop) {
+	if addr == nil {
+		return lb.eventLoops[0]
+	}
+	var h maphash.Hash
+	h.SetSeed(lb.seed)
+	key := addr.String()
+	if host, _, err := net.SplitHostPort(key); err == nil {
+		key = host
+	}
+	_, _ = h.WriteString(key)
+	idx := h.Sum64() % uint64(len(lb.eventLoops))
+	return lb.eventLoops[idx]
+}
+
+func (lb *sourceAddrHashLoadBalancer) Iterate(callback func(int, *eventloop) bool) {
+	for i, el := range lb.eventLoops {
+		if !callback(i, el) {
+			break
+		}
+	}
+}
+
+// WithLoadBalancer sets the strategy used to distribute accepted connections across
+// the server's sub-reactors. The default, if this option isn't supplied, is
+// round-robin.
+func WithLoadBalancer(lb LoadBalancer) Option {
+	return func(opts *Options) {
+		opts.LoadBalancer = lb
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRoundRobinLoadBalancerCyclesInRegistrationOrder(t *testing.T) {
+	lb := newRoundRobinLoadBalancer()
+	els := []*eventloop{{}, {}, {}}
+	for _, el := range els {
+		lb.Register(el)
+	}
+
+	for i := 0; i < len(els)*2; i++ {
+		got := lb.Next(nil)
+		want := els[i%len(els)]
+		if got != want {
+			t.Fatalf("iteration %d: got loop %p, want %p", i, got, want)
+		}
+	}
+}
+
+func TestLeastConnectionsLoadBalancerPicksSmallest(t *testing.T) {
+	lb := newLeastConnectionsLoadBalancer()
+	busy := &eventloop{connCount: 10}
+	idle := &eventloop{connCount: 1}
+	medium := &eventloop{connCount: 5}
+	lb.Register(busy)
+	lb.Register(idle)
+	lb.Register(medium)
+
+	if got := lb.Next(nil); got != idle {
+		t.Fatalf("got %p, want the least-loaded loop %p", got, idle)
+	}
+}
+
+func TestSourceAddrHashLoadBalancerIsStableAndSpreads(t *testing.T) {
+	lb := newSourceAddrHashLoadBalancer()
+	els := []*eventloop{{}, {}, {}, {}}
+	for _, el := range els {
+		lb.Register(el)
+	}
+
+	addr1 := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5555}
+	addr2 := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 6666}
+
+	first := lb.Next(addr1)
+	for i := 0; i < 5; i++ {
+		if got := lb.Next(addr1); got != first {
+			t.Fatalf("same address hashed to a different loop across calls: %p != %p", got, first)
+		}
+	}
+
+	seen := map[*eventloop]bool{lb.Next(addr1): true, lb.Next(addr2): true}
+	for i := 0; i < 64; i++ {
+		seen[lb.Next(&net.TCPAddr{IP: net.IPv4(10, 0, 1, byte(i)), Port: 7000})] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("expected distinct client addresses to spread over more than one loop")
+	}
+}
+
+func TestLoadBalancerIterateVisitsEveryRegisteredLoop(t *testing.T) {
+	for _, lb := range []LoadBalancer{
+		newRoundRobinLoadBalancer(),
+		newLeastConnectionsLoadBalancer(),
+		newSourceAddrHashLoadBalancer(),
+	} {
+		els := []*eventloop{{}, {}, {}}
+		for _, el := range els {
+			lb.Register(el)
+		}
+
+		visited := make(map[int]*eventloop)
+		lb.Iterate(func(i int, el *eventloop) bool {
+			visited[i] = el
+			return true
+		})
+		if len(visited) != len(els) {
+			t.Fatalf("Iterate visited %d loops, want %d", len(visited), len(els))
+		}
+
+		count := 0
+		lb.Iterate(func(i int, el *eventloop) bool {
+			count++
+			return false
+		})
+		if count != 1 {
+			t.Fatalf("Iterate didn't stop early when callback returned false, visited %d", count)
+		}
+	}
+}
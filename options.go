@@ -0,0 +1,144 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/zjllib/gnet/internal/logging"
+)
+
+// Options are configured by the caller of Serve via one or more Option values, the
+// usual functional-options pattern: each With* function below returns an Option that
+// sets one field.
+type Options struct {
+	// Multicore indicates whether the server will be effectively created with
+	// multiple event-loops. See the doc comment on Server.Multicore.
+	Multicore bool
+
+	// NumEventLoop is the number of sub-reactors to run. If left at 0, Serve picks
+	// runtime.NumCPU() when Multicore is set and 1 otherwise.
+	NumEventLoop int
+
+	// ReusePort is reported back through Server.ReusePort. The listener does not set
+	// SO_REUSEPORT on the listening socket.
+	ReusePort bool
+
+	// TCPKeepAlive sets the SO_KEEPALIVE period for accepted TCP connections. A value
+	// <= 0 leaves keep-alive as the listener's Accept set it up.
+	TCPKeepAlive time.Duration
+
+	// Logger, if set, replaces logging.DefaultLogger when Serve is called; the
+	// previous logger is not restored when Serve returns.
+	Logger logging.Logger
+
+	// LockOSThread pins every event-loop goroutine to its own OS thread via
+	// runtime.LockOSThread, at the cost of limiting NumEventLoop to 10,000.
+	LockOSThread bool
+
+	// IOPoller records the netpoll backend chosen with WithIOPoller. Serve does not
+	// read it; see PollerIOUring.
+	IOPoller PollerType
+
+	// LoadBalancer decides which event-loop a new connection is handed to. Defaults
+	// to round-robin.
+	LoadBalancer LoadBalancer
+
+	// IdleTimeout is the default SetIdleTimeout applied to every accepted
+	// connection.
+	IdleTimeout time.Duration
+
+	// Codec frames inbound messages for React and encodes the out bytes React returns;
+	// see WithCodec. Without it, React receives raw, unframed bytes exactly as they
+	// arrived.
+	Codec Codec
+
+	// ShutdownTimeout bounds how long Shutdown waits for connections to drain when
+	// called with a context that carries no deadline of its own.
+	ShutdownTimeout time.Duration
+}
+
+// Option configures one field of Options; Serve accepts any number of them.
+type Option func(*Options)
+
+// loadOptions applies opts over a zero-valued Options and fills in defaults for
+// anything a caller didn't set.
+func loadOptions(opts ...Option) Options {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.NumEventLoop <= 0 {
+		if options.Multicore {
+			options.NumEventLoop = runtime.NumCPU()
+		} else {
+			options.NumEventLoop = 1
+		}
+	}
+	if options.LoadBalancer == nil {
+		options.LoadBalancer = newRoundRobinLoadBalancer()
+	}
+	return options
+}
+
+// WithMulticore sets Options.Multicore.
+func WithMulticore(multicore bool) Option {
+	return func(opts *Options) {
+		opts.Multicore = multicore
+	}
+}
+
+// WithNumEventLoop sets Options.NumEventLoop.
+func WithNumEventLoop(numEventLoop int) Option {
+	return func(opts *Options) {
+		opts.NumEventLoop = numEventLoop
+	}
+}
+
+// WithReusePort sets Options.ReusePort.
+func WithReusePort(reusePort bool) Option {
+	return func(opts *Options) {
+		opts.ReusePort = reusePort
+	}
+}
+
+// WithTCPKeepAlive sets Options.TCPKeepAlive.
+func WithTCPKeepAlive(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.TCPKeepAlive = d
+	}
+}
+
+// WithLogger sets Options.Logger.
+func WithLogger(logger logging.Logger) Option {
+	return func(opts *Options) {
+		opts.Logger = logger
+	}
+}
+
+// WithLockOSThread sets Options.LockOSThread.
+func WithLockOSThread(lockOSThread bool) Option {
+	return func(opts *Options) {
+		opts.LockOSThread = lockOSThread
+	}
+}
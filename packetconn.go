@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"net"
+	"time"
+)
+
+// packetConn is the Conn implementation for a single UDP datagram. Unlike conn, it
+// has no backing socket or ring buffer that outlives the one packet it wraps, so
+// ReadN/ShiftN operate on a plain slice and the deadline setters are no-ops: a
+// "connection" that's really just one already-fully-received datagram has nothing
+// left to time out.
+type packetConn struct {
+	pc     net.PacketConn
+	remote net.Addr
+	ctx    interface{}
+	data   []byte
+}
+
+func (c *packetConn) Context() interface{}       { return c.ctx }
+func (c *packetConn) SetContext(ctx interface{}) { c.ctx = ctx }
+func (c *packetConn) LocalAddr() net.Addr        { return c.pc.LocalAddr() }
+func (c *packetConn) RemoteAddr() net.Addr       { return c.remote }
+
+func (c *packetConn) Read() []byte { return c.data }
+
+func (c *packetConn) ResetBuffer() { c.data = nil }
+
+func (c *packetConn) ReadN(n int) (int, []byte) {
+	if n > len(c.data) {
+		n = len(c.data)
+	}
+	return n, c.data[:n]
+}
+
+func (c *packetConn) ShiftN(n int) int {
+	if n > len(c.data) {
+		n = len(c.data)
+	}
+	c.data = c.data[n:]
+	return n
+}
+
+func (c *packetConn) BufferLength() int { return len(c.data) }
+
+func (c *packetConn) SendTo(buf []byte) error {
+	_, err := c.pc.WriteTo(buf, c.remote)
+	return err
+}
+
+func (c *packetConn) AsyncWrite(buf []byte) error { return c.SendTo(buf) }
+
+func (c *packetConn) Wake() error { return nil }
+
+func (c *packetConn) Close() error { return nil }
+
+func (c *packetConn) SetReadDeadline(t time.Time) error    { return nil }
+func (c *packetConn) SetWriteDeadline(t time.Time) error   { return nil }
+func (c *packetConn) SetIdleTimeout(d time.Duration) error { return nil }
@@ -0,0 +1,175 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"net"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// server is the running state behind a public Server handle: the listener, the
+// resolved options, the event-loops it started and the load balancer distributing
+// accepted connections across them.
+type server struct {
+	ln           *listener
+	opts         Options
+	handler      EventHandler
+	loadBalancer LoadBalancer
+	eventLoops   []*eventloop
+
+	shuttingDown int32
+}
+
+// serve starts opts.NumEventLoop event-loops, runs handler.OnInitComplete, then blocks
+// accepting connections (or, for a udp listener, reading packets) until the listener
+// is closed by Serve's caller or by Shutdown.
+func serve(handler EventHandler, ln *listener, opts Options) error {
+	svr := &server{ln: ln, opts: opts, handler: handler, loadBalancer: opts.LoadBalancer}
+
+	for i := 0; i < opts.NumEventLoop; i++ {
+		el := newEventLoop(i, handler, opts.Codec)
+		svr.eventLoops = append(svr.eventLoops, el)
+		svr.loadBalancer.Register(el)
+		go func(el *eventloop) {
+			if opts.LockOSThread {
+				runtime.LockOSThread()
+			}
+			el.run()
+		}(el)
+	}
+
+	pub := Server{
+		svr:          svr,
+		Multicore:    opts.Multicore,
+		Addr:         ln.Addr(),
+		NumEventLoop: len(svr.eventLoops),
+		ReusePort:    opts.ReusePort,
+		TCPKeepAlive: opts.TCPKeepAlive,
+	}
+
+	if action := handler.OnInitComplete(pub); action == Shutdown {
+		svr.stopEventLoops()
+		return nil
+	}
+
+	go svr.tickLoop(handler)
+
+	var err error
+	if ln.pc != nil {
+		err = svr.servePacket()
+	} else {
+		err = svr.acceptLoop()
+	}
+
+	svr.stopEventLoops()
+	handler.OnShutdown(pub)
+	return err
+}
+
+func (svr *server) stopEventLoops() {
+	for _, el := range svr.eventLoops {
+		el.stop()
+	}
+}
+
+func (svr *server) acceptLoop() error {
+	for {
+		nc, err := svr.ln.ln.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&svr.shuttingDown) == 1 {
+				return nil
+			}
+			return err
+		}
+
+		// Accept can race Shutdown: the kernel may have already handed back a
+		// connection before svr.ln.close() takes effect. Reject it here rather than
+		// registering it, so it isn't left dangling past closeAllConns's snapshot.
+		if atomic.LoadInt32(&svr.shuttingDown) == 1 {
+			nc.Close()
+			continue
+		}
+
+		if tc, ok := nc.(*net.TCPConn); ok && svr.opts.TCPKeepAlive > 0 {
+			_ = tc.SetKeepAlive(true)
+			_ = tc.SetKeepAlivePeriod(svr.opts.TCPKeepAlive)
+		}
+
+		el := svr.loadBalancer.Next(nc.RemoteAddr())
+		c := newConn(nc, el)
+		go el.serveConn(c, svr.opts.IdleTimeout)
+	}
+}
+
+// servePacket services a udp listener. Each datagram is delivered to React as a
+// complete, self-contained message via an ephemeral packetConn: UDP has no persistent
+// socket to attach a ring buffer or timers to, so unlike conn, packetConn carries no
+// state beyond the one packet it wraps.
+func (svr *server) servePacket() error {
+	buf := make([]byte, 64*1024)
+	for {
+		n, addr, err := svr.ln.pc.ReadFrom(buf)
+		if err != nil {
+			if atomic.LoadInt32(&svr.shuttingDown) == 1 {
+				return nil
+			}
+			return err
+		}
+
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		c := &packetConn{pc: svr.ln.pc, remote: addr, data: data}
+
+		out, action := svr.handler.OnOpened(c)
+		if len(out) > 0 {
+			c.AsyncWrite(out)
+		}
+		if action == None {
+			out, action = svr.handler.React(c.Read(), c)
+			if len(out) > 0 {
+				c.AsyncWrite(out)
+			}
+		}
+		svr.handler.OnClosed(c, nil)
+
+		if action == Shutdown {
+			atomic.StoreInt32(&svr.shuttingDown, 1)
+			return nil
+		}
+	}
+}
+
+// tickLoop drives handler.Tick() for the lifetime of the server, on its own goroutine.
+func (svr *server) tickLoop(handler EventHandler) {
+	for {
+		delay, action := handler.Tick()
+		if action == Shutdown || atomic.LoadInt32(&svr.shuttingDown) == 1 {
+			return
+		}
+		if delay <= 0 {
+			return
+		}
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/zjllib/gnet/errors"
+	"github.com/zjllib/gnet/internal/logging"
+)
+
+// WithShutdownTimeout sets the default context timeout Shutdown waits for in-flight
+// connections to drain before force-closing whatever is left. It only applies when
+// Shutdown is called with a context that carries no deadline of its own.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.ShutdownTimeout = d
+	}
+}
+
+// Shutdown stops the server from accepting new connections and waits for every
+// existing connection to finish its in-flight React call and flush any pending
+// AsyncWrite data before returning, mirroring the semantics of
+// net/http.Server.Shutdown. If ctx is canceled or its deadline passes first, any
+// connections still open are force-closed and their OnClosed fires with
+// gnet/errors.ErrServerShutdown.
+//
+// Returning Action == Shutdown from an EventHandler callback does not call Shutdown:
+// from OnInitComplete it makes Serve return before accepting connections, from
+// OnOpened or React on a udp listener it stops the packet loop without draining, from
+// a tcp connection's OnOpened or React it closes only that connection, and from Tick
+// it stops only the tick loop.
+func (s Server) Shutdown(ctx context.Context) error {
+	svr := s.svr
+
+	if !atomic.CompareAndSwapInt32(&svr.shuttingDown, 0, 1) {
+		return nil // already shutting down
+	}
+	sniffErrorAndLog(svr.ln.close())
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && svr.opts.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, svr.opts.ShutdownTimeout)
+		defer cancel()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		for s.CountConnections() > 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		// The drain goroutine above and ctx's deadline can become ready at the same
+		// moment; only force-close (and log about it) if something is actually still
+		// open, so a shutdown that finished draining just in time doesn't also report
+		// a spurious "still open" error.
+		if s.CountConnections() > 0 {
+			logging.DefaultLogger.Errorf("shutdown deadline reached with connections still open, force-closing")
+			svr.loadBalancer.Iterate(func(_ int, el *eventloop) bool {
+				el.closeAllConns(errors.ErrServerShutdown)
+				return true
+			})
+		}
+	}
+
+	return nil
+}
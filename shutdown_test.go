@@ -0,0 +1,186 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zjllib/gnet/errors"
+)
+
+// shutdownTestHandler records the error every OnClosed call fires with, so tests can
+// tell a graceful close (nil) apart from a Shutdown force-close (ErrServerShutdown).
+type shutdownTestHandler struct {
+	EventServer
+	closed chan error
+}
+
+func (h *shutdownTestHandler) OnClosed(c Conn, err error) (action Action) {
+	if h.closed != nil {
+		h.closed <- err
+	}
+	return
+}
+
+// newShutdownTestServer builds a server with a single event-loop and a real, bound
+// listener, without going through Serve/serve: the tests below drive accept and
+// Shutdown themselves so they can control exactly when each connection is registered.
+func newShutdownTestServer(t *testing.T, handler EventHandler) (*server, Server) {
+	t.Helper()
+	ln, err := initListener("tcp", "127.0.0.1:0", false)
+	if err != nil {
+		t.Fatalf("initListener: %v", err)
+	}
+	t.Cleanup(func() { ln.close() })
+
+	opts := loadOptions()
+	svr := &server{ln: ln, opts: opts, handler: handler, loadBalancer: opts.LoadBalancer}
+	el := newEventLoop(0, handler, opts.Codec)
+	svr.eventLoops = append(svr.eventLoops, el)
+	svr.loadBalancer.Register(el)
+	go el.run()
+	t.Cleanup(el.stop)
+
+	return svr, Server{svr: svr}
+}
+
+func TestShutdownIsIdempotent(t *testing.T) {
+	_, pub := newShutdownTestServer(t, &shutdownTestHandler{})
+
+	if err := pub.Shutdown(context.Background()); err != nil {
+		t.Fatalf("first Shutdown: %v", err)
+	}
+	if err := pub.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown should be a no-op, got: %v", err)
+	}
+}
+
+func TestShutdownWaitsForConnectionsToDrain(t *testing.T) {
+	handler := &shutdownTestHandler{closed: make(chan error, 1)}
+	svr, pub := newShutdownTestServer(t, handler)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	c := newConn(serverConn, svr.eventLoops[0])
+	go svr.eventLoops[0].serveConn(c, 0)
+
+	// Give serveConn a moment to register the connection before Shutdown runs, so the
+	// drain loop actually observes one open connection instead of racing to zero.
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- pub.Shutdown(context.Background()) }()
+
+	// Let the peer go away on its own; Shutdown should return once it does, well
+	// before any deadline would have forced it.
+	clientConn.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not return after its only connection drained")
+	}
+
+	select {
+	case err := <-handler.closed:
+		if err == errors.ErrServerShutdown {
+			t.Fatal("connection that drained on its own was force-closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnClosed was never called")
+	}
+}
+
+func TestShutdownForceClosesAfterDeadline(t *testing.T) {
+	handler := &shutdownTestHandler{closed: make(chan error, 1)}
+	svr, pub := newShutdownTestServer(t, handler)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	c := newConn(serverConn, svr.eventLoops[0])
+	go svr.eventLoops[0].serveConn(c, 0)
+
+	time.Sleep(10 * time.Millisecond)
+
+	// The peer is never closed, so Shutdown can only finish by hitting ctx's deadline
+	// and force-closing what's left.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := pub.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Shutdown took %v, expected it to return shortly after its deadline", elapsed)
+	}
+
+	select {
+	case err := <-handler.closed:
+		if err != errors.ErrServerShutdown {
+			t.Fatalf("OnClosed fired with err = %v, want errors.ErrServerShutdown", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnClosed was never called for the force-closed connection")
+	}
+}
+
+func TestAcceptLoopRejectsConnectionsAcceptedDuringShutdown(t *testing.T) {
+	handler := &shutdownTestHandler{closed: make(chan error, 1)}
+	svr, pub := newShutdownTestServer(t, handler)
+
+	acceptErr := make(chan error, 1)
+	go func() { acceptErr <- svr.acceptLoop() }()
+
+	// Mark the server as already shutting down, bypassing Shutdown's own CAS so this
+	// test can dial in underneath acceptLoop's shuttingDown check deterministically,
+	// the same way a real Accept racing svr.ln.close() would.
+	atomic.StoreInt32(&svr.shuttingDown, 1)
+
+	clientConn, err := net.Dial("tcp", svr.ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer clientConn.Close()
+
+	// acceptLoop should close the raw connection straight back rather than handing it
+	// to an event-loop: reads on the client side should observe EOF shortly.
+	clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1)
+	if _, err := clientConn.Read(buf); err == nil {
+		t.Fatal("expected the connection accepted during shutdown to be closed, got a successful read")
+	}
+
+	if got := pub.CountConnections(); got != 0 {
+		t.Fatalf("CountConnections() = %d, want 0: connection accepted during shutdown should never be registered", got)
+	}
+
+	svr.ln.close()
+	<-acceptErr
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"time"
+
+	"github.com/zjllib/gnet/errors"
+)
+
+// timingWheelSlots is the number of buckets in each event-loop's hashed timing
+// wheel. Every tick, the loop advances to the next slot and expires whatever
+// deadlines landed there; deadlines further out than one full revolution simply wrap
+// around, stay in their slot and are checked again on the next pass, the standard
+// trick for keeping a timing wheel's slot count independent of how far out a deadline
+// can be set.
+const timingWheelSlots = 512
+
+// timingWheelTick is how often an event-loop advances its timing wheel by one slot.
+// It bounds how late a deadline can fire relative to when it elapsed.
+const timingWheelTick = 100 * time.Millisecond
+
+// timer is a single pending deadline tracked by a timingWheel: a read deadline, write
+// deadline or idle timeout for one connection. A *timer handle returned by add is the
+// caller's ticket to cancel or replace that exact entry in O(1) via remove, without
+// ever having to search the wheel for it.
+//
+// owner is typed as interface{} rather than *conn so that timingWheel itself can be
+// unit-tested without a real *conn; every production caller (conn_deadline.go) always
+// passes the owning *conn, and tickTimingWheel asserts it back to that type.
+type timer struct {
+	owner    interface{}
+	kind     timerKind
+	deadline time.Time
+
+	slot       int
+	prev, next *timer
+}
+
+type timerKind int
+
+const (
+	timerKindRead timerKind = iota
+	timerKindWrite
+	timerKindIdle
+)
+
+// timingWheel is a hashed timing wheel owned by a single event-loop: every operation
+// is called from that loop's own goroutine (driven by its tick), so insertion,
+// cancellation and expiry are all O(1) with no locking.
+type timingWheel struct {
+	slots      [timingWheelSlots]*timer
+	currentPos int
+	lastTick   time.Time
+}
+
+func newTimingWheel() *timingWheel {
+	return &timingWheel{lastTick: time.Time{}}
+}
+
+// add inserts a deadline for conn into the wheel and returns the *timer handle to it.
+// If old is non-nil (the caller's previous timer of the same kind for this
+// connection), it is unlinked first via remove, which is an O(1) pointer operation,
+// not a search.
+func (tw *timingWheel) add(old *timer, owner interface{}, kind timerKind, deadline time.Time) *timer {
+	tw.remove(old)
+
+	now := tw.lastTick
+	if now.IsZero() {
+		now = time.Now()
+	}
+	// Round up: a slot reached before deadline would leave the timer unexpired there
+	// until the wheel's next revolution.
+	ticksAway := int((deadline.Sub(now) + timingWheelTick - 1) / timingWheelTick)
+	if ticksAway < 1 {
+		ticksAway = 1
+	}
+	slot := (tw.currentPos + ticksAway) % timingWheelSlots
+
+	t := &timer{owner: owner, kind: kind, deadline: deadline, slot: slot}
+	tw.link(t)
+	return t
+}
+
+func (tw *timingWheel) link(t *timer) {
+	head := tw.slots[t.slot]
+	t.next = head
+	if head != nil {
+		head.prev = t
+	}
+	tw.slots[t.slot] = t
+}
+
+// remove cancels a pending timer previously returned by add, in O(1): t already knows
+// its own slot and its neighbors in that slot's list, so no scan is needed. remove(nil)
+// and removing a timer that already left the wheel (removed or expired) are no-ops, so
+// callers can pass a possibly-stale handle unconditionally.
+func (tw *timingWheel) remove(t *timer) {
+	if t == nil || (t.prev == nil && tw.slots[t.slot] != t) {
+		return
+	}
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		tw.slots[t.slot] = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.prev, t.next = nil, nil
+}
+
+// advance moves the wheel forward by one slot per elapsed tick since it was last
+// called, expiring (and reporting via callback) every timer whose deadline has
+// actually passed. A timer landing in a slot the wheel is passing through but whose
+// absolute deadline is still in the future (it wrapped at least once) simply stays
+// linked in that slot until the wheel's next pass through it, instead of firing early.
+// A slot's expired timers are all unlinked before callback runs for any of them, so a
+// callback that removes other timers cannot cut the scan of that slot short.
+func (tw *timingWheel) advance(now time.Time, callback func(*timer)) {
+	if tw.lastTick.IsZero() {
+		tw.lastTick = now
+		return
+	}
+
+	ticks := int(now.Sub(tw.lastTick) / timingWheelTick)
+	for i := 0; i < ticks; i++ {
+		tw.currentPos = (tw.currentPos + 1) % timingWheelSlots
+		t := tw.slots[tw.currentPos]
+		var expired []*timer
+		for t != nil {
+			next := t.next
+			if !now.Before(t.deadline) {
+				tw.remove(t)
+				expired = append(expired, t)
+			}
+			t = next
+		}
+		for _, t := range expired {
+			callback(t)
+		}
+	}
+	if ticks > 0 {
+		tw.lastTick = now
+	}
+}
+
+// tickTimingWheel advances el's timing wheel and closes every connection whose
+// deadline just expired. It's called once per loop iteration from the event-loop's
+// own tick, the same goroutine that owns el.tw, so no synchronization is needed.
+func (el *eventloop) tickTimingWheel(now time.Time) {
+	el.tw.advance(now, func(t *timer) {
+		el.closeConn(t.owner.(*conn), errForTimerKind(t.kind))
+	})
+}
+
+func errForTimerKind(kind timerKind) error {
+	switch kind {
+	case timerKindRead:
+		return errors.ErrReadTimeout
+	case timerKindWrite:
+		return errors.ErrWriteTimeout
+	default:
+		return errors.ErrIdleTimeout
+	}
+}
+
+// WithIdleTimeout sets the default SetIdleTimeout applied to every connection
+// accepted by the server; an individual connection can still override it by calling
+// Conn.SetIdleTimeout directly.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.IdleTimeout = d
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingWheelExpiresAfterDeadline(t *testing.T) {
+	tw := newTimingWheel()
+	owner := new(int)
+
+	base := time.Unix(0, 0)
+	tw.advance(base, func(*timer) { t.Fatal("unexpected expiry on the priming call") })
+
+	deadline := base.Add(2 * timingWheelTick)
+	tw.add(nil, owner, timerKindRead, deadline)
+
+	var expired []*timer
+	tw.advance(base.Add(timingWheelTick), func(tm *timer) { expired = append(expired, tm) })
+	if len(expired) != 0 {
+		t.Fatalf("timer fired a tick early: %v", expired)
+	}
+
+	tw.advance(base.Add(3*timingWheelTick), func(tm *timer) { expired = append(expired, tm) })
+	if len(expired) != 1 {
+		t.Fatalf("expected exactly one expiry, got %d", len(expired))
+	}
+	if expired[0].owner != owner || expired[0].kind != timerKindRead {
+		t.Fatalf("expired timer has the wrong owner/kind: %+v", expired[0])
+	}
+}
+
+func TestTimingWheelRemoveCancelsBeforeExpiry(t *testing.T) {
+	tw := newTimingWheel()
+	owner := new(int)
+	base := time.Unix(0, 0)
+	tw.advance(base, nil)
+
+	th := tw.add(nil, owner, timerKindIdle, base.Add(timingWheelTick))
+	tw.remove(th)
+
+	fired := false
+	tw.advance(base.Add(2*timingWheelTick), func(*timer) { fired = true })
+	if fired {
+		t.Fatal("removed timer still fired")
+	}
+}
+
+func TestTimingWheelAddReplacesPreviousHandle(t *testing.T) {
+	tw := newTimingWheel()
+	owner := new(int)
+	base := time.Unix(0, 0)
+	tw.advance(base, nil)
+
+	first := tw.add(nil, owner, timerKindWrite, base.Add(timingWheelTick))
+	second := tw.add(first, owner, timerKindWrite, base.Add(5*timingWheelTick))
+
+	var expired []*timer
+	tw.advance(base.Add(2*timingWheelTick), func(tm *timer) { expired = append(expired, tm) })
+	if len(expired) != 0 {
+		t.Fatalf("the replaced (first) deadline still fired: %v", expired)
+	}
+
+	tw.advance(base.Add(6*timingWheelTick), func(tm *timer) { expired = append(expired, tm) })
+	if len(expired) != 1 || expired[0] != second {
+		t.Fatalf("expected only the replacement timer to fire, got %v", expired)
+	}
+}
+
+func TestTimingWheelRemoveNilIsNoop(t *testing.T) {
+	tw := newTimingWheel()
+	tw.remove(nil) // must not panic
+}